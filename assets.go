@@ -0,0 +1,70 @@
+package main
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/gzip"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*
+var embeddedTemplates embed.FS
+
+//go:embed static/*
+var embeddedStatic embed.FS
+
+// devMode включается флагом -dev и переключает загрузку шаблонов и статики с
+// зашитого в бинарь embed.FS на диск, для живой перезагрузки при разработке.
+var devMode bool
+
+// loadTemplates регистрирует HTML-шаблоны: с диска в режиме -dev, иначе — из
+// embed.FS, собранного в бинарь на этапе сборки.
+func loadTemplates(router *gin.Engine) {
+	funcMap := template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+	}
+
+	if devMode {
+		router.SetFuncMap(funcMap)
+		router.LoadHTMLGlob("templates/*")
+		return
+	}
+
+	tmpl := template.Must(template.New("").Funcs(funcMap).ParseFS(embeddedTemplates, "templates/*"))
+	router.SetHTMLTemplate(tmpl)
+}
+
+// staticCacheControl выставляет короткий Cache-Control для /static/*. Имена файлов
+// здесь не хэшируются по содержимому (нет шага сборки, который бы это делал), так
+// что долгий immutable-TTL был бы небезопасен — правка CSS/JS под тем же именем
+// должна дойти до браузера в разумное время.
+func staticCacheControl() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/static/") {
+			c.Header("Cache-Control", "public, max-age=300")
+		}
+		c.Next()
+	}
+}
+
+// registerStatic монтирует /static/*: с диска в режиме -dev, иначе — из embed.FS,
+// и включает gzip-сжатие ответов через gin-contrib/gzip; brotli не поддерживается.
+func registerStatic(router *gin.Engine) {
+	router.Use(gzip.Gzip(gzip.DefaultCompression))
+	router.Use(staticCacheControl())
+
+	if devMode {
+		router.Static("/static", "./static")
+		return
+	}
+
+	staticRoot, err := fs.Sub(embeddedStatic, "static")
+	if err != nil {
+		fatal("failed to mount embedded static assets", "error", err)
+	}
+	router.StaticFS("/static", http.FS(staticRoot))
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig собирает параметры провайдера из OIDC_ISSUER/OIDC_CLIENT_ID/... env-переменных.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	GroupsClaim  string
+}
+
+// OIDCProvider реализует Provider поверх authorization code flow произвольного OIDC IdP
+// (Keycloak, Authentik, внутренний SSO).
+type OIDCProvider struct {
+	cfg      OIDCConfig
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// NewOIDCProvider делает OIDC discovery по IssuerURL и возвращает готовый к использованию провайдер.
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig) (*OIDCProvider, error) {
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	return &OIDCProvider{
+		cfg:      cfg,
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email", cfg.GroupsClaim},
+		},
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string { return "oidc" }
+
+// Login редиректит на authorization endpoint IdP, сохраняя state в cookie для защиты от CSRF.
+func (p *OIDCProvider) Login(c *gin.Context) {
+	state := randomState()
+	c.SetCookie("oidc_state", state, 300, "/auth/oidc", "", false, true)
+	c.Redirect(http.StatusFound, p.oauth2.AuthCodeURL(state))
+}
+
+// Callback обменивает code на токен, проверяет id_token и достаёт username/группы из claims.
+func (p *OIDCProvider) Callback(c *gin.Context) (*UserInfo, error) {
+	expectedState, err := c.Cookie("oidc_state")
+	if err != nil || c.Query("state") != expectedState {
+		return nil, fmt.Errorf("invalid oauth2 state")
+	}
+
+	token, err := p.oauth2.Exchange(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("no id_token in oauth2 response")
+	}
+
+	idToken, err := p.verifier.Verify(c.Request.Context(), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id_token verification failed: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %w", err)
+	}
+
+	username, _ := claims["preferred_username"].(string)
+	if username == "" {
+		username, _ = claims["sub"].(string)
+	}
+
+	return &UserInfo{
+		Username: username,
+		Groups:   groupsFromClaim(claims[p.cfg.GroupsClaim]),
+	}, nil
+}
+
+func groupsFromClaim(raw interface{}) []string {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	groups := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			groups = append(groups, s)
+		}
+	}
+	return groups
+}
+
+func randomState() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
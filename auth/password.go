@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidCredentials отличает отказ по логину/паролю от сбоя связи с источником проверки,
+// чтобы вызывающий код (страница логина) мог показать разные сообщения об ошибке.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// CheckFunc проверяет логин/пароль во внешней системе (например, AuthAPI телефонной книги).
+type CheckFunc func(username, password string) (*UserInfo, error)
+
+// PasswordProvider — существовавший ранее механизм логин/пароль, обёрнутый в интерфейс Provider.
+type PasswordProvider struct {
+	check CheckFunc
+}
+
+// NewPasswordProvider создаёт провайдер логин/пароль поверх переданной функции проверки.
+func NewPasswordProvider(check CheckFunc) *PasswordProvider {
+	return &PasswordProvider{check: check}
+}
+
+func (p *PasswordProvider) Name() string { return "password" }
+
+// Login для этого провайдера ничего не делает: форма логина рендерится обработчиком /login.
+func (p *PasswordProvider) Login(c *gin.Context) {}
+
+// Callback проверяет username/password, переданные формой логина.
+func (p *PasswordProvider) Callback(c *gin.Context) (*UserInfo, error) {
+	username := c.PostForm("username")
+	password := c.PostForm("password")
+	return p.check(username, password)
+}
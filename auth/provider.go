@@ -0,0 +1,51 @@
+// Package auth содержит провайдеры аутентификации, стоящие за общим интерфейсом Provider.
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// UserInfo — результат успешной аутентификации: логин и группы для последующей проверки hasAccess.
+type UserInfo struct {
+	Username string
+	Groups   []string
+}
+
+// Provider описывает один способ входа: редирект на форму/внешний IdP и обработку обратного вызова.
+type Provider interface {
+	// Name возвращает идентификатор провайдера, используемый в AUTH_PROVIDERS и маршрутах /auth/<name>/*.
+	Name() string
+	// Login инициирует вход (например, редиректит на внешний IdP); провайдер, чью форму
+	// рендерит отдельный обработчик, может здесь ничего не делать.
+	Login(c *gin.Context)
+	// Callback завершает вход и возвращает данные пользователя.
+	Callback(c *gin.Context) (*UserInfo, error)
+}
+
+// Registry хранит включённые провайдеры в порядке, заданном AUTH_PROVIDERS.
+type Registry struct {
+	providers map[string]Provider
+	order     []string
+}
+
+// NewRegistry создаёт пустой реестр провайдеров.
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register добавляет провайдер в реестр.
+func (r *Registry) Register(p Provider) {
+	if _, exists := r.providers[p.Name()]; !exists {
+		r.order = append(r.order, p.Name())
+	}
+	r.providers[p.Name()] = p
+}
+
+// Get возвращает провайдер по имени.
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names возвращает имена включённых провайдеров в порядке регистрации.
+func (r *Registry) Names() []string {
+	return r.order
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// healthzHandler сообщает только о том, что процесс жив, без обращения к внешним зависимостям.
+func healthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyzHandler проверяет готовность принимать трафик: доступность БД и внешнего AuthAPI.
+func readyzHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "db": err.Error()})
+		return
+	}
+
+	if err := pingAuthAPI(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "auth_api": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+func pingAuthAPI(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, cfg.AuthAPI, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
+
+// runAdminServer поднимает /healthz, /readyz и /metrics на отдельном порту (ADMIN_PORT),
+// чтобы их не требовалось публично открывать вместе с основным приложением.
+func runAdminServer(adminPort string) {
+	admin := gin.New()
+	admin.GET("/healthz", healthzHandler)
+	admin.GET("/readyz", readyzHandler)
+	admin.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	logger.Info("admin server starting", "port", adminPort)
+	if err := admin.Run(":" + adminPort); err != nil {
+		logger.Error("admin server stopped", "error", err)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+
+	"telefon/store"
+)
+
+// csvColumns задаёт порядок колонок и для экспорта, и для импорта.
+var csvColumns = []string{"last_name", "first_name", "middle_name", "position", "phone", "email", "building", "comments", "status"}
+
+func employeeToRow(emp Employee) []string {
+	return []string{emp.LastName, emp.FirstName, emp.MiddleName, emp.Position, emp.Phone, emp.Email, emp.Building, emp.Comments, emp.Status}
+}
+
+func employeeFromRow(row []string) Employee {
+	get := func(i int) string {
+		if i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	return Employee{
+		LastName:   get(0),
+		FirstName:  get(1),
+		MiddleName: get(2),
+		Position:   get(3),
+		Phone:      get(4),
+		Email:      get(5),
+		Building:   get(6),
+		Comments:   get(7),
+		Status:     get(8),
+	}
+}
+
+const exportAllLimit = 100000
+
+func exportCSVHandler(c *gin.Context) {
+	employees, err := employeeStore.List(c.Request.Context(), store.Filter{Limit: exportAllLimit})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="employees.csv"`)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(csvColumns)
+	for _, emp := range employees {
+		w.Write(employeeToRow(emp))
+	}
+	w.Flush()
+}
+
+func exportXLSXHandler(c *gin.Context) {
+	employees, err := employeeStore.List(c.Request.Context(), store.Filter{Limit: exportAllLimit})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	f := excelize.NewFile()
+	sheet := "Employees"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	for i, col := range csvColumns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, col)
+	}
+	for r, emp := range employees {
+		for i, v := range employeeToRow(emp) {
+			cell, _ := excelize.CoordinatesToCellName(i+1, r+2)
+			f.SetCellValue(sheet, cell, v)
+		}
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="employees.xlsx"`)
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+
+	if err := f.Write(c.Writer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+}
+
+// vcardExportHandler отдаёт одну запись в формате RFC 6350, пригодном для импорта в Outlook/телефон.
+func vcardExportHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	emp, err := employeeStore.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+		return
+	}
+
+	vcard := employeeToVCard(*emp)
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%d.vcf"`, emp.ID))
+	c.Data(http.StatusOK, "text/vcard; charset=utf-8", []byte(vcard))
+}
+
+// vcardEscapeRe покрывает символы, которые RFC 6350 §3.4 требует экранировать в значении свойства.
+var vcardEscapeRe = regexp.MustCompile(`[\\,;\r\n]`)
+
+// vcardEscape экранирует `\`, `,`, `;` и переводы строк обратным слешем, как того требует RFC 6350 §3.4,
+// чтобы свободный текст (ФИО, комментарии) не мог сломать структуру vCard лишними свойствами/строками.
+func vcardEscape(s string) string {
+	return vcardEscapeRe.ReplaceAllStringFunc(s, func(m string) string {
+		switch m {
+		case "\r":
+			return ""
+		case "\n":
+			return `\n`
+		default:
+			return `\` + m
+		}
+	})
+}
+
+func employeeToVCard(emp Employee) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\r\n")
+	b.WriteString("VERSION:4.0\r\n")
+	fmt.Fprintf(&b, "FN:%s %s %s\r\n", vcardEscape(emp.FirstName), vcardEscape(emp.MiddleName), vcardEscape(emp.LastName))
+	fmt.Fprintf(&b, "N:%s;%s;%s;;\r\n", vcardEscape(emp.LastName), vcardEscape(emp.FirstName), vcardEscape(emp.MiddleName))
+	b.WriteString("ORG:Company\r\n")
+	fmt.Fprintf(&b, "TITLE:%s\r\n", vcardEscape(emp.Position))
+	fmt.Fprintf(&b, "TEL;TYPE=work:%s\r\n", vcardEscape(emp.Phone))
+	if emp.Email != "" {
+		fmt.Fprintf(&b, "EMAIL:%s\r\n", vcardEscape(emp.Email))
+	}
+	if emp.Comments != "" {
+		fmt.Fprintf(&b, "NOTE:%s\r\n", vcardEscape(emp.Comments))
+	}
+	b.WriteString("END:VCARD\r\n")
+	return b.String()
+}
+
+// importEmployeesHandler принимает CSV/XLSX-файл. С ?dry_run=true только валидирует
+// и возвращает ошибки построчно, без обращения к БД.
+func importEmployeesHandler(c *gin.Context) {
+	session := getSession(c)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	rows, err := parseImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rowErrors []store.RowError
+	valid := make([]Employee, 0, len(rows))
+	for i, emp := range rows {
+		if err := validateEmployee(&emp); err != nil {
+			rowErrors = append(rowErrors, store.RowError{Row: i + 1, Message: err.Error()})
+			continue
+		}
+		valid = append(valid, emp)
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	if dryRun || len(rowErrors) > 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"total":  len(rows),
+			"valid":  len(valid),
+			"errors": rowErrors,
+			"dryRun": dryRun,
+		})
+		return
+	}
+
+	naturalKey := store.NaturalKey(cfg.ImportNaturalKey)
+	summary, err := employeeStore.Import(c.Request.Context(), valid, naturalKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "summary": summary})
+		return
+	}
+
+	logAudit("import", 0, session.Username, fmt.Sprintf("created=%d updated=%d total=%d", summary.Created, summary.Updated, summary.Total))
+	c.JSON(http.StatusOK, summary)
+}
+
+func parseImportFile(fileHeader *multipart.FileHeader) ([]Employee, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		return parseCSVImport(file)
+	case ".xlsx":
+		return parseXLSXImport(file)
+	default:
+		return nil, fmt.Errorf("unsupported file extension, expected .csv or .xlsx")
+	}
+}
+
+func parseCSVImport(r multipart.File) ([]Employee, error) {
+	reader := csv.NewReader(r)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	employees := make([]Employee, 0, len(records)-1)
+	for _, row := range records[1:] {
+		employees = append(employees, employeeFromRow(row))
+	}
+	return employees, nil
+}
+
+func parseXLSXImport(r multipart.File) ([]Employee, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	employees := make([]Employee, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		employees = append(employees, employeeFromRow(row))
+	}
+	return employees, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Параметры scrypt для локальных паролей: N=32768, r=8, p=1, см. hashLocalPassword.
+const (
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 64
+	scryptSalt   = 32
+)
+
+// LocalUser — учётная запись в local_users, используемая как резерв на случай
+// недоступности AUTH_API.
+type LocalUser struct {
+	Username     string
+	PasswordHash string
+	Groups       []string
+	LastLogin    sql.NullTime
+}
+
+// hashLocalPassword кодирует пароль как "scrypt:N:r:p$salt$hex" со случайной солью.
+func hashLocalPassword(password string) (string, error) {
+	salt := make([]byte, scryptSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	derived, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	return fmt.Sprintf("scrypt:%d:%d:%d$%x$%x", scryptN, scryptR, scryptP, salt, derived), nil
+}
+
+// verifyLocalPassword сравнивает пароль с хэшем в формате hashLocalPassword, в константное время.
+func verifyLocalPassword(password, encoded string) (bool, error) {
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) != 3 {
+		return false, fmt.Errorf("malformed password hash")
+	}
+
+	params := strings.SplitN(parts[0], ":", 4)
+	if len(params) != 4 || params[0] != "scrypt" {
+		return false, fmt.Errorf("unsupported password hash scheme: %s", parts[0])
+	}
+
+	n, err := strconv.Atoi(params[1])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt N: %w", err)
+	}
+	r, err := strconv.Atoi(params[2])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt r: %w", err)
+	}
+	p, err := strconv.Atoi(params[3])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt p: %w", err)
+	}
+
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("invalid salt encoding: %w", err)
+	}
+	want, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false, fmt.Errorf("invalid hash encoding: %w", err)
+	}
+
+	got, err := scrypt.Key([]byte(password), salt, n, r, p, len(want))
+	if err != nil {
+		return false, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
+
+// bootstrapAdminUser создаёт аварийную учётную запись администратора из
+// BOOTSTRAP_ADMIN_USER/BOOTSTRAP_ADMIN_PASSWORD, если она ещё не существует.
+// Вызывается один раз при старте, после применения миграций.
+func bootstrapAdminUser(ctx context.Context, cfg Config) error {
+	if cfg.BootstrapAdminUser == "" || cfg.BootstrapAdminPassword == "" {
+		return nil
+	}
+
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM local_users WHERE username = $1)`, cfg.BootstrapAdminUser).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check bootstrap admin: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	hash, err := hashLocalPassword(cfg.BootstrapAdminPassword)
+	if err != nil {
+		return err
+	}
+
+	groupsJSON, _ := json.Marshal([]string{"Администраторы домена"})
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO local_users (username, password_hash, groups) VALUES ($1, $2, $3)`,
+		cfg.BootstrapAdminUser, hash, string(groupsJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap admin: %w", err)
+	}
+
+	logger.Info("bootstrap admin created", "username", cfg.BootstrapAdminUser)
+	return nil
+}
+
+// checkLocalAuth проверяет логин/пароль по таблице local_users и обновляет last_login при успехе.
+func checkLocalAuth(ctx context.Context, username, password string) (*AuthResponse, error) {
+	var hash, groupsJSON string
+	err := db.QueryRowContext(ctx,
+		`SELECT password_hash, groups FROM local_users WHERE username = $1`, username,
+	).Scan(&hash, &groupsJSON)
+	if err == sql.ErrNoRows {
+		return &AuthResponse{Success: false}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local user: %w", err)
+	}
+
+	ok, err := verifyLocalPassword(password, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &AuthResponse{Success: false}, nil
+	}
+
+	var groups []string
+	json.Unmarshal([]byte(groupsJSON), &groups)
+
+	if _, err := db.ExecContext(ctx, `UPDATE local_users SET last_login = CURRENT_TIMESTAMP WHERE username = $1`, username); err != nil {
+		logger.Error("failed to update last_login", "username", username, "error", err)
+	}
+
+	return &AuthResponse{Success: true, Groups: groups}, nil
+}
+
+// listLocalUsersHandler отдаёт /admin/users: список локальных аккаунтов для экстренного входа.
+func listLocalUsersHandler(c *gin.Context) {
+	rows, err := db.QueryContext(c.Request.Context(), `SELECT username, groups, last_login FROM local_users ORDER BY username`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var users []LocalUser
+	for rows.Next() {
+		var u LocalUser
+		var groupsJSON string
+		if err := rows.Scan(&u.Username, &groupsJSON, &u.LastLogin); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		json.Unmarshal([]byte(groupsJSON), &u.Groups)
+		users = append(users, u)
+	}
+
+	c.HTML(http.StatusOK, "admin_users.html", gin.H{
+		"Users":     users,
+		"CSRFToken": csrfToken(c),
+	})
+}
+
+// createLocalUserHandler заводит или перезаписывает локального пользователя с указанными группами.
+func createLocalUserHandler(c *gin.Context) {
+	username := c.PostForm("username")
+	password := c.PostForm("password")
+	groups := strings.Split(c.PostForm("groups"), ",")
+	for i := range groups {
+		groups[i] = strings.TrimSpace(groups[i])
+	}
+
+	if username == "" || password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username and password are required"})
+		return
+	}
+
+	hash, err := hashLocalPassword(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupsJSON, _ := json.Marshal(groups)
+	_, err = db.ExecContext(c.Request.Context(), `
+		INSERT INTO local_users (username, password_hash, groups) VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO UPDATE SET password_hash = $2, groups = $3
+	`, username, hash, string(groupsJSON))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := getSession(c)
+	logAudit("local_user_upsert", 0, session.Username, username)
+	c.Redirect(http.StatusFound, "/admin/users")
+}
+
+// deleteLocalUserHandler удаляет локальный аккаунт.
+func deleteLocalUserHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	if _, err := db.ExecContext(c.Request.Context(), `DELETE FROM local_users WHERE username = $1`, username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := getSession(c)
+	logAudit("local_user_delete", 0, session.Username, username)
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -2,35 +2,32 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+
+	"telefon/auth"
+	"telefon/migrations"
+	"telefon/store"
 )
 
-// Структуры данных
-type Employee struct {
-	ID         int       `json:"id" db:"id"`
-	LastName   string    `json:"last_name" db:"last_name"`
-	FirstName  string    `json:"first_name" db:"first_name"`
-	MiddleName string    `json:"middle_name" db:"middle_name"`
-	Position   string    `json:"position" db:"position"`
-	Phone      string    `json:"phone" db:"phone"`
-	Email      string    `json:"email" db:"email"`
-	Building   string    `json:"building" db:"building"`
-	Comments   string    `json:"comments" db:"comments"`
-	Status     string    `json:"status" db:"status"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-}
+// Employee — запись сотрудника в справочнике; тип живёт в пакете store, здесь
+// оставлен алиас, чтобы не переписывать существующие сигнатуры хендлеров и шаблонов.
+type Employee = store.Employee
 
 type Config struct {
 	DBHost     string
@@ -42,6 +39,27 @@ type Config struct {
 	DBSSLMode  string
 	ServerPort string
 	AuthAPI    string
+
+	SessionSecret   string
+	SessionStore    string
+	RedisAddr       string
+	RedisPassword   string
+	SessionIdleTTL  time.Duration
+	SessionAbsTTL   time.Duration
+
+	AuthProviders       []string
+	OIDCIssuer          string
+	OIDCClientID        string
+	OIDCClientSecret    string
+	OIDCRedirectURL     string
+	OIDCGroupsClaim     string
+
+	ImportNaturalKey string
+
+	AdminPort string
+
+	BootstrapAdminUser     string
+	BootstrapAdminPassword string
 }
 
 // Структуры для авторизации
@@ -56,15 +74,29 @@ type AuthResponse struct {
 }
 
 type UserSession struct {
-	Username string
-	Groups   []string
-	LoggedIn bool
+	Username    string
+	Groups      []string
+	Role        string
+	LoggedIn    bool
+	OTPVerified bool
+}
+
+// AuditLog записывает, кто и когда изменил запись сотрудника
+type AuditLog struct {
+	ID         int       `json:"id" db:"id"`
+	Action     string    `json:"action" db:"action"`
+	EmployeeID int       `json:"employee_id" db:"employee_id"`
+	Username   string    `json:"username" db:"username"`
+	Details    string    `json:"details" db:"details"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // Глобальные переменные
 var (
-	db  *sql.DB
-	cfg Config
+	db            *sql.DB
+	cfg           Config
+	authReg       *auth.Registry
+	employeeStore store.EmployeeStore
 )
 
 func loadConfig() Config {
@@ -80,6 +112,27 @@ func loadConfig() Config {
 		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		AuthAPI:    getEnv("AUTH_API", "https://109.it25.su/api/auth?groups=1"),
+
+		SessionSecret:  getEnv("SESSION_SECRET", ""),
+		SessionStore:   getEnv("SESSION_STORE", "cookie"),
+		RedisAddr:      getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:  getEnv("REDIS_PASSWORD", ""),
+		SessionIdleTTL: parseDurationEnv("SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		SessionAbsTTL:  parseDurationEnv("SESSION_ABSOLUTE_TIMEOUT", 12*time.Hour),
+
+		AuthProviders:    strings.Split(getEnv("AUTH_PROVIDERS", "password"), ","),
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+
+		ImportNaturalKey: getEnv("IMPORT_NATURAL_KEY", "email"),
+
+		AdminPort: getEnv("ADMIN_PORT", "9090"),
+
+		BootstrapAdminUser:     getEnv("BOOTSTRAP_ADMIN_USER", ""),
+		BootstrapAdminPassword: getEnv("BOOTSTRAP_ADMIN_PASSWORD", ""),
 	}
 }
 
@@ -90,61 +143,32 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// Middleware для проверки авторизации
-func authRequired() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		session := getSession(c)
-		if !session.LoggedIn {
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
-			return
+func parseDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
 		}
-		c.Next()
 	}
+	return defaultValue
 }
 
-// Получение сессии из cookie
-func getSession(c *gin.Context) *UserSession {
-	var session UserSession
-
-	username, err := c.Cookie("username")
-	if err != nil {
-		return &session
-	}
-
-	groupsCookie, err := c.Cookie("usergroups")
-	if err != nil {
-		return &session
+// checkAuth проверяет логин/пароль через внешний AUTH_API. Если API недоступен по сети,
+// прозрачно откатывается на таблицу local_users, чтобы отказ внешнего сервиса не блокировал вход.
+func checkAuth(username, password string) (*AuthResponse, error) {
+	authResp, err := checkRemoteAuth(username, password)
+	if err == nil {
+		return authResp, nil
 	}
 
-	var groups []string
-	json.Unmarshal([]byte(groupsCookie), &groups)
-
-	session = UserSession{
-		Username: username,
-		Groups:   groups,
-		LoggedIn: true,
+	if _, ok := err.(*url.Error); !ok {
+		return nil, err
 	}
 
-	return &session
+	logger.Error("auth api unreachable, falling back to local users", "error", err)
+	return checkLocalAuth(context.Background(), username, password)
 }
 
-// Сохранение сессии в cookie
-func setSession(c *gin.Context, username string, groups []string) {
-	groupsJSON, _ := json.Marshal(groups)
-
-	c.SetCookie("username", username, 3600, "/", "", false, true)
-	c.SetCookie("usergroups", string(groupsJSON), 3600, "/", "", false, true)
-}
-
-// Очистка сессии
-func clearSession(c *gin.Context) {
-	c.SetCookie("username", "", -1, "/", "", false, true)
-	c.SetCookie("usergroups", "", -1, "/", "", false, true)
-}
-
-// Проверка авторизации через API
-func checkAuth(username, password string) (*AuthResponse, error) {
+func checkRemoteAuth(username, password string) (*AuthResponse, error) {
 	authReq := AuthRequest{
 		Username: username,
 		Password: password,
@@ -178,18 +202,143 @@ func checkAuth(username, password string) (*AuthResponse, error) {
 	return &authResp, nil
 }
 
+// initAuthProviders собирает реестр провайдеров аутентификации согласно cfg.AuthProviders.
+func initAuthProviders(ctx context.Context, cfg Config) *auth.Registry {
+	reg := auth.NewRegistry()
+
+	for _, name := range cfg.AuthProviders {
+		switch strings.TrimSpace(name) {
+		case "password":
+			reg.Register(auth.NewPasswordProvider(func(username, password string) (*auth.UserInfo, error) {
+				resp, err := checkAuth(username, password)
+				if err != nil {
+					return nil, err
+				}
+				if !resp.Success {
+					return nil, auth.ErrInvalidCredentials
+				}
+				return &auth.UserInfo{Username: username, Groups: resp.Groups}, nil
+			}))
+		case "oidc":
+			provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+				IssuerURL:    cfg.OIDCIssuer,
+				ClientID:     cfg.OIDCClientID,
+				ClientSecret: cfg.OIDCClientSecret,
+				RedirectURL:  cfg.OIDCRedirectURL,
+				GroupsClaim:  cfg.OIDCGroupsClaim,
+			})
+			if err != nil {
+				fatal("failed to initialize oidc provider", "error", err)
+			}
+			reg.Register(provider)
+		case "":
+			// пустая запись из AUTH_PROVIDERS, игнорируем
+		default:
+			fatal("unknown auth provider", "provider", name)
+		}
+	}
+
+	return reg
+}
+
+// oidcLoginHandler инициирует authorization code flow через провайдер "oidc"
+func oidcLoginHandler(c *gin.Context) {
+	provider, ok := authReg.Get("oidc")
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "oidc provider is not configured"})
+		return
+	}
+	provider.Login(c)
+}
+
+// oidcCallbackHandler обрабатывает обратный вызов IdP и заводит обычную сессию
+func oidcCallbackHandler(c *gin.Context) {
+	provider, ok := authReg.Get("oidc")
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "oidc provider is not configured"})
+		return
+	}
+
+	info, err := provider.Callback(c)
+	if err != nil {
+		authOutcomesTotal.WithLabelValues("failure").Inc()
+		c.HTML(http.StatusOK, "login.html", gin.H{
+			"Error":     "Ошибка входа через OIDC: " + err.Error(),
+			"CSRFToken": csrfToken(c),
+		})
+		return
+	}
+
+	if !hasAccess(info.Groups) {
+		authOutcomesTotal.WithLabelValues("forbidden").Inc()
+		c.HTML(http.StatusOK, "login.html", gin.H{
+			"Error":     "Обратитесь за доступом к администратору",
+			"CSRFToken": csrfToken(c),
+		})
+		return
+	}
+
+	authOutcomesTotal.WithLabelValues("success").Inc()
+	setSession(c, info.Username, info.Groups)
+	redirectAfterLogin(c, info.Username, info.Groups)
+}
+
+// Роли и сопоставление с группами AD/LDAP
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// roleRank задаёт порядок ролей для сравнения в roleRequired
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// groupRoles сопоставляет группы AD/LDAP с ролью в приложении
+var groupRoles = map[string]string{
+	"Администраторы домена": RoleAdmin,
+	"sys.admins":            RoleAdmin,
+	"Администрация":         RoleAdmin,
+	"Редакторы справочника":  RoleEditor,
+	"Сотрудники":             RoleViewer,
+}
+
+// roleForGroups возвращает наивысшую роль, доступную пользователю по его группам
+func roleForGroups(groups []string) string {
+	best := ""
+	for _, g := range groups {
+		role, ok := groupRoles[g]
+		if !ok {
+			continue
+		}
+		if best == "" || roleRank[role] > roleRank[best] {
+			best = role
+		}
+	}
+	return best
+}
+
 // Проверка доступа пользователя к системе
 func hasAccess(groups []string) bool {
-	allowedGroups := []string{"Администраторы домена", "sys.admins", "Администрация"}
+	return roleForGroups(groups) != ""
+}
 
-	for _, userGroup := range groups {
-		for _, allowedGroup := range allowedGroups {
-			if userGroup == allowedGroup {
-				return true
-			}
+// Middleware, требующий роль не ниже minRole. Должен идти после authRequired().
+func roleRequired(minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := getSession(c)
+		if roleRank[session.Role] < roleRank[minRole] {
+			c.HTML(http.StatusForbidden, "error.html", gin.H{
+				"Error": "Недостаточно прав для выполнения операции",
+			})
+			c.Abort()
+			return
 		}
+		c.Next()
 	}
-	return false
 }
 
 // Обработчики маршрутов
@@ -200,7 +349,9 @@ func loginHandler(c *gin.Context) {
 		return
 	}
 
-	c.HTML(http.StatusOK, "login.html", gin.H{})
+	c.HTML(http.StatusOK, "login.html", gin.H{
+		"CSRFToken": csrfToken(c),
+	})
 }
 
 func loginPostHandler(c *gin.Context) {
@@ -209,34 +360,57 @@ func loginPostHandler(c *gin.Context) {
 
 	if username == "" || password == "" {
 		c.HTML(http.StatusOK, "login.html", gin.H{
-			"Error": "Введите логин и пароль",
+			"Error":     "Введите логин и пароль",
+			"CSRFToken": csrfToken(c),
 		})
 		return
 	}
 
-	authResp, err := checkAuth(username, password)
-	if err != nil {
+	provider, ok := authReg.Get("password")
+	if !ok {
 		c.HTML(http.StatusOK, "login.html", gin.H{
-			"Error": "Ошибка подключения к серверу авторизации",
+			"Error":     "Вход по логину и паролю не настроен",
+			"CSRFToken": csrfToken(c),
 		})
 		return
 	}
 
-	if !authResp.Success {
+	info, err := provider.Callback(c)
+	if err != nil {
+		authOutcomesTotal.WithLabelValues("failure").Inc()
+
+		msg := "Ошибка подключения к серверу авторизации"
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			msg = "Проверьте логин или пароль, ошибка авторизации"
+		}
 		c.HTML(http.StatusOK, "login.html", gin.H{
-			"Error": "Проверьте логин или пароль, ошибка авторизации",
+			"Error":     msg,
+			"CSRFToken": csrfToken(c),
 		})
 		return
 	}
 
-	if !hasAccess(authResp.Groups) {
+	if !hasAccess(info.Groups) {
+		authOutcomesTotal.WithLabelValues("forbidden").Inc()
 		c.HTML(http.StatusOK, "login.html", gin.H{
-			"Error": "Обратитесь за доступом к администратору",
+			"Error":     "Обратитесь за доступом к администратору",
+			"CSRFToken": csrfToken(c),
 		})
 		return
 	}
 
-	setSession(c, username, authResp.Groups)
+	authOutcomesTotal.WithLabelValues("success").Inc()
+	setSession(c, info.Username, info.Groups)
+	redirectAfterLogin(c, info.Username, info.Groups)
+}
+
+// redirectAfterLogin отправляет пользователя на /otp/verify, если для его групп обязательна
+// 2FA и она уже настроена, иначе — на главную страницу.
+func redirectAfterLogin(c *gin.Context, username string, groups []string) {
+	if needsOTP(groups) && isOTPEnrolled(c.Request.Context(), username) {
+		c.Redirect(http.StatusFound, "/otp/verify")
+		return
+	}
 	c.Redirect(http.StatusFound, "/")
 }
 
@@ -245,27 +419,45 @@ func logoutHandler(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/login")
 }
 
-func indexHandler(c *gin.Context) {
-	session := getSession(c)
-
-	employees, err := getEmployees()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// parsePagination читает ?limit=&offset= из запроса, игнорируя некорректные значения.
+func parsePagination(c *gin.Context) (limit, offset int) {
+	limit, _ = strconv.Atoi(c.Query("limit"))
+	offset, _ = strconv.Atoi(c.Query("offset"))
+	if offset < 0 {
+		offset = 0
 	}
+	return limit, offset
+}
 
+func countActive(employees []Employee) int {
 	activeCount := 0
 	for _, emp := range employees {
 		if emp.Status == "работает" {
 			activeCount++
 		}
 	}
+	return activeCount
+}
+
+func indexHandler(c *gin.Context) {
+	session := getSession(c)
+	limit, offset := parsePagination(c)
+	limit = store.EffectiveLimit(limit)
+
+	employees, err := employeeStore.List(c.Request.Context(), store.Filter{Limit: limit, Offset: offset})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"Employees":   employees,
-		"ActiveCount": activeCount,
+		"SearchQuery": "",
+		"ActiveCount": countActive(employees),
 		"Username":    session.Username,
 		"TotalCount":  len(employees),
+		"Limit":       limit,
+		"Offset":      offset,
 	})
 }
 
@@ -279,36 +471,38 @@ func searchHandler(c *gin.Context) {
 		return
 	}
 
-	employees, err := searchEmployees(query)
+	limit, offset := parsePagination(c)
+	limit = store.EffectiveLimit(limit)
+	searchQueriesTotal.Inc()
+
+	employees, err := employeeStore.Search(c.Request.Context(), query, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	activeCount := 0
-	for _, emp := range employees {
-		if emp.Status == "работает" {
-			activeCount++
-		}
-	}
-
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"Employees":   employees,
 		"SearchQuery": query,
-		"ActiveCount": activeCount,
+		"ActiveCount": countActive(employees),
 		"Username":    session.Username,
 		"TotalCount":  len(employees),
+		"Limit":       limit,
+		"Offset":      offset,
 	})
 }
 
 // Функции работы с БД
-func initDB() error {
+
+// connectDB создаёт целевую базу (если её ещё нет) и возвращает соединение с ней.
+// Используется как обычным запуском сервера, так и подкомандой migrate.
+func connectDB(cfg Config) (*sql.DB, error) {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s sslmode=%s",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBSSLMode)
 
 	adminDb, err := sql.Open("postgres", connStr)
 	if err != nil {
-		return fmt.Errorf("failed to connect to PostgreSQL: %v", err)
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %v", err)
 	}
 	defer adminDb.Close()
 
@@ -319,234 +513,344 @@ func initDB() error {
 		)
 	`, cfg.DBName).Scan(&dbExists)
 	if err != nil {
-		return fmt.Errorf("failed to check database existence: %v", err)
+		return nil, fmt.Errorf("failed to check database existence: %v", err)
 	}
 
 	if !dbExists {
 		_, err = adminDb.Exec(fmt.Sprintf("CREATE DATABASE %s", cfg.DBName))
 		if err != nil {
-			return fmt.Errorf("failed to create database: %v", err)
+			return nil, fmt.Errorf("failed to create database: %v", err)
 		}
-		log.Printf("Database '%s' created successfully", cfg.DBName)
+		logger.Info("database created", "name", cfg.DBName)
 	}
 
 	connStr = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode)
 
-	db, err = sql.Open("postgres", connStr)
+	conn, err := sql.Open("postgres", connStr)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	if err = db.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %v", err)
+	if err := conn.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	err = createTableIfNotExists()
+	return conn, nil
+}
+
+func initDB() error {
+	conn, err := connectDB(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create table: %v", err)
+		return err
 	}
+	db = conn
 
-	log.Println("Database initialized successfully")
+	if err := store.ValidateTableName(cfg.DBTable); err != nil {
+		return err
+	}
+
+	employeeStore, err = store.NewPostgresStore(db, cfg.DBTable)
+	if err != nil {
+		return fmt.Errorf("failed to initialize employee store: %v", err)
+	}
+
+	logger.Info("database initialized")
 	return nil
 }
 
-func createTableIfNotExists() error {
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id SERIAL PRIMARY KEY,
-			last_name VARCHAR(100) NOT NULL,
-			first_name VARCHAR(100) NOT NULL,
-			middle_name VARCHAR(100),
-			position VARCHAR(200) NOT NULL,
-			phone VARCHAR(20) NOT NULL,
-			email VARCHAR(150),
-			building VARCHAR(50) CHECK (building IN ('Цветоносная', 'Феофанова', 'Везде', 'Удаленный')),
-			comments TEXT,
-			status VARCHAR(20) CHECK (status IN ('работает', 'уволен', 'внешний')),
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`, cfg.DBTable)
+// runMigrateCommand реализует `telefon migrate up|down|status`, применяя схему
+// отдельно от запуска сервера, чтобы миграции не зависели от того, какой инстанс стартовал первым.
+func runMigrateCommand(args []string) {
+	if len(args) != 1 {
+		fatal("usage: telefon migrate up|down|status")
+	}
 
-	_, err := db.Exec(query)
-	if err != nil {
-		return err
+	if err := store.ValidateTableName(cfg.DBTable); err != nil {
+		fatal(err.Error())
 	}
 
-	var count int
-	err = db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", cfg.DBTable)).Scan(&count)
+	conn, err := connectDB(cfg)
 	if err != nil {
-		return err
+		fatal(err.Error())
 	}
+	defer conn.Close()
 
-	if count == 0 {
-		err = insertSampleData()
+	runner := migrations.NewRunner(conn, cfg.DBTable)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		if err := runner.Up(ctx); err != nil {
+			fatal("migrate up failed", "error", err)
+		}
+		logger.Info("migrations applied")
+	case "down":
+		if err := runner.Down(ctx); err != nil {
+			fatal("migrate down failed", "error", err)
+		}
+		logger.Info("last migration rolled back")
+	case "status":
+		entries, err := runner.Status(ctx)
 		if err != nil {
-			return err
+			fatal("migrate status failed", "error", err)
+		}
+		for _, e := range entries {
+			state := "pending"
+			if e.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s: %s\n", e.Version, e.Name, state)
 		}
-		log.Printf("Inserted sample records")
+	default:
+		fatal("unknown migrate subcommand", "subcommand", args[0])
 	}
-
-	return nil
 }
 
-func insertSampleData() error {
-	query := fmt.Sprintf(`
-		INSERT INTO %s (last_name, first_name, middle_name, position, phone, email, building, comments, status) VALUES
-		($1, $2, $3, $4, $5, $6, $7, $8, $9),
-		($10, $11, $12, $13, $14, $15, $16, $17, $18),
-		($19, $20, $21, $22, $23, $24, $25, $26, $27),
-		($28, $29, $30, $31, $32, $33, $34, $35, $36)
-	`, cfg.DBTable)
 
-	_, err := db.Exec(query,
-		"Иванов", "Иван", "Иванович", "Старший разработчик",
-		"+7-999-123-45-67", "ivanov@company.com", "Цветоносная",
-		"Team lead backend team", "работает",
+func apiEmployeesHandler(c *gin.Context) {
+	limit, offset := parsePagination(c)
 
-		"Петрова", "Мария", "Сергеевна", "Менеджер проектов",
-		"+7-999-123-45-68", "petrova@company.com", "Феофанова",
-		"PMO department", "работает",
+	employees, err := employeeStore.List(c.Request.Context(), store.Filter{Limit: limit, Offset: offset})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-		"Сидоров", "Алексей", "Петрович", "Бизнес-аналитик",
-		"+7-999-123-45-69", "sidorov@company.com", "Удаленный",
-		"Внешний консультант", "внешний",
+	c.JSON(http.StatusOK, employees)
+}
 
-		"Козлова", "Ольга", "Владимировна", "Дизайнер",
-		"+7-999-123-45-70", "kozlova@company.com", "Везде",
-		"UI/UX designer", "работает",
-	)
+func getEmployeeHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
 
-	return err
+	emp, err := employeeStore.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, emp)
 }
 
-func getEmployees() ([]Employee, error) {
-	query := fmt.Sprintf(`
-		SELECT id, last_name, first_name, middle_name, position, 
-			   phone, email, building, comments, status, created_at 
-		FROM %s 
-		ORDER BY last_name, first_name
-	`, cfg.DBTable)
+// Допустимые значения перечислений, совпадают с CHECK-ограничениями таблицы
+var (
+	validBuildings = map[string]bool{
+		"Цветоносная": true,
+		"Феофанова":   true,
+		"Везде":       true,
+		"Удаленный":   true,
+	}
+	validStatuses = map[string]bool{
+		"работает": true,
+		"уволен":   true,
+		"внешний":  true,
+	}
+	phoneRe = regexp.MustCompile(`^\+?[0-9][0-9\-\s]{6,19}$`)
+	emailRe = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+)
 
-	rows, err := db.Query(query)
-	if err != nil {
-		return nil, err
+// validateEmployee проверяет поля сотрудника перед записью в БД
+func validateEmployee(emp *Employee) error {
+	if emp.LastName == "" || emp.FirstName == "" {
+		return fmt.Errorf("фамилия и имя обязательны")
 	}
-	defer rows.Close()
+	if !validBuildings[emp.Building] {
+		return fmt.Errorf("недопустимое значение building: %s", emp.Building)
+	}
+	if !validStatuses[emp.Status] {
+		return fmt.Errorf("недопустимое значение status: %s", emp.Status)
+	}
+	if !phoneRe.MatchString(emp.Phone) {
+		return fmt.Errorf("некорректный формат телефона: %s", emp.Phone)
+	}
+	if emp.Email != "" && !emailRe.MatchString(emp.Email) {
+		return fmt.Errorf("некорректный формат email: %s", emp.Email)
+	}
+	return nil
+}
 
-	var employees []Employee
-	for rows.Next() {
-		var emp Employee
-		err := rows.Scan(
-			&emp.ID, &emp.LastName, &emp.FirstName, &emp.MiddleName,
-			&emp.Position, &emp.Phone, &emp.Email, &emp.Building,
-			&emp.Comments, &emp.Status, &emp.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		employees = append(employees, emp)
+// logAudit записывает факт изменения записи сотрудника в журнал аудита
+func logAudit(action string, employeeID int, username, details string) {
+	_, err := db.Exec(
+		`INSERT INTO audit_log (action, employee_id, username, details) VALUES ($1, $2, $3, $4)`,
+		action, employeeID, username, details,
+	)
+	if err != nil {
+		logger.Error("failed to write audit log", "error", err)
 	}
+}
 
-	return employees, nil
+func employeeFromForm(c *gin.Context) Employee {
+	return Employee{
+		LastName:   c.PostForm("last_name"),
+		FirstName:  c.PostForm("first_name"),
+		MiddleName: c.PostForm("middle_name"),
+		Position:   c.PostForm("position"),
+		Phone:      c.PostForm("phone"),
+		Email:      c.PostForm("email"),
+		Building:   c.PostForm("building"),
+		Comments:   c.PostForm("comments"),
+		Status:     c.PostForm("status"),
+	}
 }
 
-func searchEmployees(query string) ([]Employee, error) {
-	sqlQuery := fmt.Sprintf(`
-		SELECT id, last_name, first_name, middle_name, position, 
-			   phone, email, building, comments, status, created_at 
-		FROM %s 
-		WHERE last_name ILIKE $1 OR first_name ILIKE $1 OR 
-			  middle_name ILIKE $1 OR position ILIKE $1 OR 
-			  phone ILIKE $1 OR email ILIKE $1
-		ORDER BY last_name, first_name
-	`, cfg.DBTable)
+func newEmployeeFormHandler(c *gin.Context) {
+	c.HTML(http.StatusOK, "employee_form.html", gin.H{
+		"Employee":  Employee{},
+		"IsNew":     true,
+		"CSRFToken": csrfToken(c),
+	})
+}
 
-	rows, err := db.Query(sqlQuery, "%"+query+"%")
+func editEmployeeFormHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return nil, err
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
 	}
-	defer rows.Close()
 
-	var employees []Employee
-	for rows.Next() {
-		var emp Employee
-		err := rows.Scan(
-			&emp.ID, &emp.LastName, &emp.FirstName, &emp.MiddleName,
-			&emp.Position, &emp.Phone, &emp.Email, &emp.Building,
-			&emp.Comments, &emp.Status, &emp.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		employees = append(employees, emp)
+	emp, err := employeeStore.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+		return
 	}
 
-	return employees, nil
+	c.HTML(http.StatusOK, "employee_form.html", gin.H{
+		"Employee":  emp,
+		"IsNew":     false,
+		"CSRFToken": csrfToken(c),
+	})
 }
 
-func apiEmployeesHandler(c *gin.Context) {
-	employees, err := getEmployees()
+func createEmployeeHandler(c *gin.Context) {
+	session := getSession(c)
+	emp := employeeFromForm(c)
+
+	if err := validateEmployee(&emp); err != nil {
+		c.HTML(http.StatusOK, "employee_form.html", gin.H{
+			"Employee":  emp,
+			"IsNew":     true,
+			"Error":     err.Error(),
+			"CSRFToken": csrfToken(c),
+		})
+		return
+	}
+
+	id, err := employeeStore.Create(c.Request.Context(), &emp)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, employees)
+	logAudit("create", id, session.Username, fmt.Sprintf("%s %s", emp.LastName, emp.FirstName))
+	c.Redirect(http.StatusFound, "/")
 }
 
-func getEmployeeHandler(c *gin.Context) {
-	idStr := c.Param("id")
+func updateEmployeeHandler(c *gin.Context) {
+	session := getSession(c)
 
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 
-	query := fmt.Sprintf(`
-		SELECT id, last_name, first_name, middle_name, position, 
-			   phone, email, building, comments, status, created_at 
-		FROM %s 
-		WHERE id = $1
-	`, cfg.DBTable)
+	emp := employeeFromForm(c)
+	emp.ID = id
 
-	var emp Employee
-	err = db.QueryRow(query, id).Scan(
-		&emp.ID, &emp.LastName, &emp.FirstName, &emp.MiddleName,
-		&emp.Position, &emp.Phone, &emp.Email, &emp.Building,
-		&emp.Comments, &emp.Status, &emp.CreatedAt,
-	)
+	if err := validateEmployee(&emp); err != nil {
+		c.HTML(http.StatusOK, "employee_form.html", gin.H{
+			"Employee":  emp,
+			"IsNew":     false,
+			"Error":     err.Error(),
+			"CSRFToken": csrfToken(c),
+		})
+		return
+	}
 
+	if err := employeeStore.Update(c.Request.Context(), &emp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logAudit("update", id, session.Username, fmt.Sprintf("%s %s", emp.LastName, emp.FirstName))
+	c.Redirect(http.StatusFound, "/")
+}
+
+func deleteEmployeeHandler(c *gin.Context) {
+	session := getSession(c)
+
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, emp)
+	if err := employeeStore.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logAudit("delete", id, session.Username, "")
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
 func main() {
+	initLogger()
 	cfg = loadConfig()
 
+	devFlag := flag.Bool("dev", false, "load templates and static assets from disk instead of the embedded build")
+	flag.Parse()
+	devMode = *devFlag
+
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "migrate" {
+		runMigrateCommand(args[1:])
+		return
+	}
+
 	if err := initDB(); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		fatal("failed to initialize database", "error", err)
 	}
+	registerDBStatsMetrics(db)
+
+	if err := bootstrapAdminUser(context.Background(), cfg); err != nil {
+		fatal("failed to bootstrap admin user", "error", err)
+	}
+
+	authReg = initAuthProviders(context.Background(), cfg)
 
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery(), requestIDMiddleware(), metricsMiddleware(), accessLogMiddleware())
 
-	router.SetFuncMap(template.FuncMap{
-		"add": func(a, b int) int { return a + b },
-	})
+	loadTemplates(router)
+	registerStatic(router)
 
-	router.LoadHTMLGlob("templates/*")
-	router.Static("/static", "./static")
+	router.Use(sessionMiddleware(cfg))
 
 	router.GET("/login", loginHandler)
-	router.POST("/login", loginPostHandler)
+	router.POST("/login", csrfRequired(), loginPostHandler)
 	router.GET("/logout", logoutHandler)
 
+	router.GET("/auth/oidc/login", oidcLoginHandler)
+	router.GET("/auth/oidc/callback", oidcCallbackHandler)
+
+	otp := router.Group("/otp")
+	otp.Use(loginRequired())
+	{
+		otp.GET("/enroll", otpEnrollHandler)
+		otp.GET("/enroll/qr.png", otpEnrollQRHandler)
+		otp.POST("/enroll", csrfRequired(), otpEnrollConfirmHandler)
+		otp.GET("/verify", otpVerifyFormHandler)
+		otp.POST("/verify", csrfRequired(), otpVerifyHandler)
+		otp.POST("/disable", otpVerifiedRequired(), csrfRequired(), otpDisableHandler)
+	}
+
 	authorized := router.Group("/")
 	authorized.Use(authRequired())
 	{
@@ -554,9 +858,28 @@ func main() {
 		authorized.GET("/search", searchHandler)
 		authorized.GET("/api/employees", apiEmployeesHandler)
 		authorized.GET("/api/employees/:id", getEmployeeHandler)
+
+		authorized.GET("/employees/new", roleRequired(RoleEditor), newEmployeeFormHandler)
+		authorized.GET("/employees/:id/edit", roleRequired(RoleEditor), editEmployeeFormHandler)
+
+		authorized.POST("/api/employees", roleRequired(RoleEditor), csrfRequired(), createEmployeeHandler)
+		authorized.PUT("/api/employees/:id", roleRequired(RoleEditor), csrfRequired(), updateEmployeeHandler)
+		authorized.DELETE("/api/employees/:id", roleRequired(RoleAdmin), csrfRequired(), deleteEmployeeHandler)
+
+		authorized.GET("/api/employees/export.csv", exportCSVHandler)
+		authorized.GET("/api/employees/export.xlsx", exportXLSXHandler)
+		authorized.GET("/api/employees/:id/vcard", vcardExportHandler)
+		authorized.POST("/api/employees/import", roleRequired(RoleEditor), csrfRequired(), importEmployeesHandler)
+
+		authorized.GET("/admin/users", roleRequired(RoleAdmin), listLocalUsersHandler)
+		authorized.POST("/admin/users", roleRequired(RoleAdmin), csrfRequired(), createLocalUserHandler)
+		authorized.DELETE("/admin/users/:username", roleRequired(RoleAdmin), csrfRequired(), deleteLocalUserHandler)
 	}
 
-	log.Printf("Server starting on http://localhost:%s", cfg.ServerPort)
-	log.Printf("Database: %s, Table: %s", cfg.DBName, cfg.DBTable)
-	log.Fatal(router.Run(":" + cfg.ServerPort))
+	go runAdminServer(cfg.AdminPort)
+
+	logger.Info("server starting", "port", cfg.ServerPort, "db", cfg.DBName, "table", cfg.DBTable)
+	if err := router.Run(":" + cfg.ServerPort); err != nil {
+		fatal("server stopped", "error", err)
+	}
 }
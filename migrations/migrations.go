@@ -0,0 +1,92 @@
+// Package migrations ships the versioned SQL schema for telefon as embedded files
+// and applies them through an in-repo runner tracking a schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+// Migration is one versioned schema change with its up/down SQL templates.
+// {{.Table}} in the SQL is substituted with the configured employees table name.
+type Migration struct {
+	Version int
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+// Load reads and orders all embedded migrations by version.
+func Load() ([]Migration, error) {
+	entries, err := sqlFS.ReadDir("sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		version, name, direction, err := parseFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := sqlFS.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		switch direction {
+		case "up":
+			m.UpSQL = string(content)
+		case "down":
+			m.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// parseFilename splits "0001_employees.up.sql" into version, name and direction.
+func parseFilename(filename string) (version int, name, direction string, err error) {
+	base := strings.TrimSuffix(filename, ".sql")
+
+	parts := strings.Split(base, ".")
+	if len(parts) != 2 {
+		return 0, "", "", fmt.Errorf("unexpected migration filename: %s", filename)
+	}
+	direction = parts[1]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", fmt.Errorf("migration %s has unknown direction %q", filename, direction)
+	}
+
+	versionAndName := strings.SplitN(parts[0], "_", 2)
+	if len(versionAndName) != 2 {
+		return 0, "", "", fmt.Errorf("migration %s is missing version prefix", filename)
+	}
+
+	version, err = strconv.Atoi(versionAndName[0])
+	if err != nil {
+		return 0, "", "", fmt.Errorf("migration %s has non-numeric version: %w", filename, err)
+	}
+
+	return version, versionAndName[1], direction, nil
+}
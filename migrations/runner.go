@@ -0,0 +1,191 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"text/template"
+)
+
+// Runner applies embedded migrations against db, tracking progress in schema_migrations.
+// table is substituted for {{.Table}} in migration SQL so the employees table name stays configurable.
+type Runner struct {
+	db    *sql.DB
+	table string
+}
+
+// NewRunner builds a Runner for the given employees table name.
+func NewRunner(db *sql.DB, table string) *Runner {
+	return &Runner{db: db, table: table}
+}
+
+// StatusEntry describes one migration and whether it has been applied.
+type StatusEntry struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+const ensureVersionTableSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(200) NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+func (r *Runner) ensureVersionTable(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, ensureVersionTableSQL)
+	return err
+}
+
+func (r *Runner) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// render substitutes {{.Table}} in a migration's SQL with the configured table name.
+func (r *Runner) render(sqlText string) (string, error) {
+	tmpl, err := template.New("migration").Parse(sqlText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse migration template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Table string }{Table: r.table}); err != nil {
+		return "", fmt.Errorf("failed to render migration template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Up applies every migration that hasn't been recorded in schema_migrations yet, in order.
+func (r *Runner) Up(ctx context.Context) error {
+	if err := r.ensureVersionTable(ctx); err != nil {
+		return err
+	}
+
+	all, err := Load()
+	if err != nil {
+		return err
+	}
+
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range all {
+		if applied[m.Version] {
+			continue
+		}
+
+		sqlText, err := r.render(m.UpSQL)
+		if err != nil {
+			return err
+		}
+
+		if err := r.applyInTx(ctx, sqlText, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+			return err
+		}); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down rolls back the single most recently applied migration.
+func (r *Runner) Down(ctx context.Context) error {
+	if err := r.ensureVersionTable(ctx); err != nil {
+		return err
+	}
+
+	all, err := Load()
+	if err != nil {
+		return err
+	}
+
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var last *Migration
+	for i := range all {
+		if applied[all[i].Version] {
+			if last == nil || all[i].Version > last.Version {
+				last = &all[i]
+			}
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+
+	sqlText, err := r.render(last.DownSQL)
+	if err != nil {
+		return err
+	}
+
+	return r.applyInTx(ctx, sqlText, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", last.Version)
+		return err
+	})
+}
+
+// applyInTx runs sqlText and then record inside a single transaction.
+func (r *Runner) applyInTx(ctx context.Context, sqlText string, record func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sqlText); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Status reports every known migration and whether it has been applied.
+func (r *Runner) Status(ctx context.Context) ([]StatusEntry, error) {
+	if err := r.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+
+	all, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StatusEntry, 0, len(all))
+	for _, m := range all {
+		entries = append(entries, StatusEntry{Version: m.Version, Name: m.Name, Applied: applied[m.Version]})
+	}
+	return entries, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// logger — структурированный логгер приложения (log/slog, JSON), заменяет прежние log.Printf/log.Fatal.
+var logger *slog.Logger
+
+func initLogger() {
+	logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+}
+
+// fatal логирует ошибку как критическую и завершает процесс, заменяя log.Fatal в местах старта приложения.
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "telefon_http_requests_total",
+		Help: "Total HTTP requests by method, route and status code",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "telefon_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds by method and route",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// authOutcomesTotal считает попытки входа по исходу: success, failure (неверные данные или сбой проверки) или forbidden (hasAccess == false).
+	authOutcomesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "telefon_auth_outcomes_total",
+		Help: "Login attempts by outcome: success, failure, forbidden",
+	}, []string{"outcome"})
+
+	searchQueriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "telefon_search_queries_total",
+		Help: "Total number of employee search queries executed",
+	})
+)
+
+// registerDBStatsMetrics публикует db.Stats() как Prometheus-гейджи; вызывается один раз после открытия соединения.
+func registerDBStatsMetrics(db *sql.DB) {
+	gauge := func(name, help string, get func() float64) {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, get)
+	}
+
+	gauge("telefon_db_open_connections", "Number of open DB connections", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	gauge("telefon_db_in_use_connections", "Number of DB connections currently in use", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	gauge("telefon_db_idle_connections", "Number of idle DB connections", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	gauge("telefon_db_wait_count", "Total number of connections waited for", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+}
+
+// requestIDMiddleware проставляет X-Request-ID (генерируя при отсутствии) и кладёт его в контекст запроса.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if id == "" {
+			id = generateRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+func generateRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// requestLogger возвращает логгер с полями request_id/username/remote_ip, привязанными к текущему запросу.
+func requestLogger(c *gin.Context) *slog.Logger {
+	username := ""
+	// Маршруты /static/* зарегистрированы до sessionMiddleware, и сессии в их контексте нет.
+	if _, ok := c.Get(sessions.DefaultKey); ok {
+		if session := getSession(c); session.LoggedIn {
+			username = session.Username
+		}
+	}
+
+	return logger.With(
+		"request_id", c.GetString("request_id"),
+		"username", username,
+		"remote_ip", c.ClientIP(),
+	)
+}
+
+// metricsMiddleware записывает счётчик запросов и гистограмму латентности по маршруту (без подстановки :id).
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	}
+}
+
+// accessLogMiddleware заменяет встроенный gin.Logger() структурированным логом через log/slog.
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		requestLogger(c).Info("http_request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+)
+
+// otpQRSize — сторона PNG с QR-кодом в пикселях; с этим запасом код уверенно
+// сканируется с экрана телефона, не раздувая страницу.
+const otpQRSize = 256
+
+// totpStep и totpSkew реализуют RFC 6238 с окном в 30 секунд и допуском ±1 шаг
+// на рассинхронизацию часов клиента.
+const (
+	totpStep        = 30 * time.Second
+	totpSkew        = 1
+	totpDigits      = 6
+	totpSecretBytes = 20
+	backupCodeCount = 10
+)
+
+// otpGroups — группы, для которых вход требует подтверждённого OTP, если 2FA у пользователя настроена (см. groupRoles).
+var otpGroups = map[string]bool{
+	"Администраторы домена": true,
+	"sys.admins":            true,
+}
+
+// needsOTP сообщает, подпадает ли хотя бы одна из групп пользователя под обязательную 2FA.
+func needsOTP(groups []string) bool {
+	for _, g := range groups {
+		if otpGroups[g] {
+			return true
+		}
+	}
+	return false
+}
+
+// generateTOTPSecret генерирует случайный секрет и кодирует его в base32 без паддинга,
+// как того ожидают приложения-аутентификаторы.
+func generateTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate otp secret: %w", err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf), nil
+}
+
+// totpCodeAt вычисляет 6-значный TOTP-код для секрета и момента времени по RFC 6238.
+func totpCodeAt(secret string, at time.Time) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid otp secret: %w", err)
+	}
+
+	counter := uint64(at.Unix()) / uint64(totpStep.Seconds())
+
+	var counterBytes [8]byte
+	binary.BigEndian.PutUint64(counterBytes[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counterBytes[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	code := truncated % pow10(totpDigits)
+	return fmt.Sprintf("%0*d", totpDigits, code), nil
+}
+
+func pow10(n int) uint32 {
+	result := uint32(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// verifyTOTPCode принимает код, если он совпадает с текущим шагом или соседним в пределах totpSkew.
+func verifyTOTPCode(secret, code string) bool {
+	code = strings.TrimSpace(code)
+	now := time.Now()
+
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		want, err := totpCodeAt(secret, now.Add(time.Duration(skew)*totpStep))
+		if err != nil {
+			return false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// otpauthURL строит otpauth://-ссылку, которую приложение-аутентификатор превращает в QR-код.
+func otpauthURL(username, secret string) string {
+	return fmt.Sprintf("otpauth://totp/Telefon:%s?secret=%s&issuer=Telefon", username, secret)
+}
+
+// generateBackupCode возвращает один 10-символьный резервный код в hex-алфавите.
+func generateBackupCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate backup code: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashBackupCode хэширует резервный код для хранения: коды одноразовые и короткие,
+// поэтому быстрый sha256 достаточен и не требует параметров, как у scrypt для паролей.
+func hashBackupCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// isOTPEnrolled сообщает, заведена ли у пользователя запись в user_otp.
+func isOTPEnrolled(ctx context.Context, username string) bool {
+	var exists bool
+	_ = db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM user_otp WHERE username = $1)`, username).Scan(&exists)
+	return exists
+}
+
+// otpEnrollHandler генерирует кандидатный секрет и резервные коды и откладывает их
+// в сессии — в user_otp они попадают только после подтверждения кодом в
+// otpEnrollConfirmHandler, чтобы голый GET (reload, prefetch, CSRF-картинка) не мог
+// молча инвалидировать уже настроенную 2FA.
+func otpEnrollHandler(c *gin.Context) {
+	session := getSession(c)
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes := make([]string, 0, backupCodeCount)
+	hashedCodes := make([]string, 0, backupCodeCount)
+	for i := 0; i < backupCodeCount; i++ {
+		code, err := generateBackupCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		codes = append(codes, code)
+		hashedCodes = append(hashedCodes, hashBackupCode(code))
+	}
+
+	hashedJSON, _ := json.Marshal(hashedCodes)
+	setOTPPending(c, secret, string(hashedJSON))
+
+	c.HTML(http.StatusOK, "otp_enroll.html", gin.H{
+		"OTPAuthURL":  otpauthURL(session.Username, secret),
+		"Secret":      secret,
+		"BackupCodes": codes,
+		"CSRFToken":   csrfToken(c),
+	})
+}
+
+// otpEnrollQRHandler отдаёт QR-код кандидатного секрета из сессии как PNG, чтобы
+// otp_enroll.html мог показать его через <img> вместо того, чтобы заставлять
+// пользователя переписывать otpauth://-ссылку вручную.
+func otpEnrollQRHandler(c *gin.Context) {
+	session := getSession(c)
+
+	secret, _, ok := getOTPPending(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	png, err := qrcode.Encode(otpauthURL(session.Username, secret), qrcode.Medium, otpQRSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// otpEnrollConfirmHandler подтверждает кандидатный секрет из сессии кодом и только
+// тогда сохраняет его (вместе с резервными кодами) в user_otp, заменяя предыдущий.
+func otpEnrollConfirmHandler(c *gin.Context) {
+	session := getSession(c)
+	code := c.PostForm("code")
+
+	secret, hashedJSON, ok := getOTPPending(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/otp/enroll")
+		return
+	}
+
+	if !verifyTOTPCode(secret, code) {
+		c.HTML(http.StatusOK, "otp_enroll.html", gin.H{
+			"OTPAuthURL": otpauthURL(session.Username, secret),
+			"Secret":     secret,
+			"Error":      "Неверный код, попробуйте ещё раз",
+			"CSRFToken":  csrfToken(c),
+		})
+		return
+	}
+
+	_, err := db.ExecContext(c.Request.Context(), `
+		INSERT INTO user_otp (username, secret, backup_codes) VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO UPDATE SET secret = $2, backup_codes = $3, enrolled_at = CURRENT_TIMESTAMP
+	`, session.Username, secret, hashedJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	clearOTPPending(c)
+	setOTPVerified(c)
+	logAudit("otp_enroll", 0, session.Username, "")
+	c.Redirect(http.StatusFound, "/")
+}
+
+// otpVerifyFormHandler рендерит форму ввода 6-значного кода при входе с обязательной 2FA.
+func otpVerifyFormHandler(c *gin.Context) {
+	c.HTML(http.StatusOK, "otp_verify.html", gin.H{
+		"CSRFToken": csrfToken(c),
+	})
+}
+
+// otpVerifyHandler проверяет код (TOTP или резервный) и помечает сессию как otp_verified.
+func otpVerifyHandler(c *gin.Context) {
+	session := getSession(c)
+	code := c.PostForm("code")
+
+	var secret, backupCodesJSON string
+	err := db.QueryRowContext(c.Request.Context(),
+		`SELECT secret, backup_codes FROM user_otp WHERE username = $1`, session.Username,
+	).Scan(&secret, &backupCodesJSON)
+	if err == sql.ErrNoRows {
+		c.HTML(http.StatusOK, "otp_verify.html", gin.H{
+			"Error":     "2FA не настроена для этой учётной записи",
+			"CSRFToken": csrfToken(c),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if verifyTOTPCode(secret, code) {
+		setOTPVerified(c)
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	if consumeBackupCode(c, session.Username, backupCodesJSON, code) {
+		setOTPVerified(c)
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	c.HTML(http.StatusOK, "otp_verify.html", gin.H{
+		"Error":     "Неверный код",
+		"CSRFToken": csrfToken(c),
+	})
+}
+
+// consumeBackupCode проверяет код против хэшей резервных кодов и, при совпадении,
+// удаляет использованный код из хранилища (коды одноразовые).
+func consumeBackupCode(c *gin.Context, username, backupCodesJSON, code string) bool {
+	var hashedCodes []string
+	if err := json.Unmarshal([]byte(backupCodesJSON), &hashedCodes); err != nil {
+		return false
+	}
+
+	submitted := hashBackupCode(code)
+	remaining := make([]string, 0, len(hashedCodes))
+	found := false
+	for _, h := range hashedCodes {
+		if !found && subtle.ConstantTimeCompare([]byte(h), []byte(submitted)) == 1 {
+			found = true
+			continue
+		}
+		remaining = append(remaining, h)
+	}
+	if !found {
+		return false
+	}
+
+	remainingJSON, _ := json.Marshal(remaining)
+	if _, err := db.ExecContext(c.Request.Context(),
+		`UPDATE user_otp SET backup_codes = $1 WHERE username = $2`, string(remainingJSON), username,
+	); err != nil {
+		logger.Error("failed to consume backup code", "username", username, "error", err)
+	}
+	return true
+}
+
+// otpDisableHandler удаляет 2FA у текущего пользователя; доступна только после успешной otp_verify.
+func otpDisableHandler(c *gin.Context) {
+	session := getSession(c)
+
+	if _, err := db.ExecContext(c.Request.Context(), `DELETE FROM user_otp WHERE username = $1`, session.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logAudit("otp_disable", 0, session.Username, "")
+	c.Redirect(http.StatusFound, "/")
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-contrib/sessions/redis"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionCookieName     = "telefon_session"
+	sessionKeyUser        = "username"
+	sessionKeyGroups      = "groups"
+	sessionKeyLoginAt     = "login_at"
+	sessionKeyLastSeen    = "last_seen"
+	sessionKeyCSRF        = "csrf_token"
+	sessionKeyOTPVerified = "otp_verified"
+
+	sessionKeyOTPPendingSecret = "otp_pending_secret"
+	sessionKeyOTPPendingCodes  = "otp_pending_codes"
+)
+
+// newSessionStore строит backend сессий согласно cfg.SessionStore (cookie по умолчанию, redis при SESSION_STORE=redis)
+func newSessionStore(cfg Config) sessions.Store {
+	if cfg.SessionSecret == "" {
+		fatal("SESSION_SECRET must be set to a random secret in production")
+	}
+
+	secret := []byte(cfg.SessionSecret)
+
+	switch cfg.SessionStore {
+	case "redis":
+		store, err := redis.NewStore(10, "tcp", cfg.RedisAddr, cfg.RedisPassword, secret)
+		if err != nil {
+			fatal("failed to initialize redis session store", "error", err)
+		}
+		return store
+	default:
+		return cookie.NewStore(secret)
+	}
+}
+
+// sessionMiddleware регистрирует gin-contrib/sessions поверх выбранного backend'а
+func sessionMiddleware(cfg Config) gin.HandlerFunc {
+	store := newSessionStore(cfg)
+	store.Options(sessions.Options{
+		Path:     "/",
+		MaxAge:   int(cfg.SessionAbsTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sessions.Sessions(sessionCookieName, store)
+}
+
+// Middleware для проверки авторизации, работает поверх sessionMiddleware.
+// Для пользователей из групп, требующих 2FA (см. otpGroups), у которых 2FA настроена,
+// дополнительно перенаправляет на /otp/verify, пока сессия не подтверждена кодом.
+func authRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := getSession(c)
+		if !session.LoggedIn {
+			clearSession(c)
+			c.Redirect(http.StatusFound, "/login")
+			c.Abort()
+			return
+		}
+
+		if needsOTP(session.Groups) && !session.OTPVerified && isOTPEnrolled(c.Request.Context(), session.Username) {
+			c.Redirect(http.StatusFound, "/otp/verify")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// loginRequired — облегчённая версия authRequired без проверки otp_verified,
+// используется маршрутами /otp/enroll и /otp/verify, которые как раз и подтверждают вторым фактором.
+func loginRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := getSession(c)
+		if !session.LoggedIn {
+			clearSession(c)
+			c.Redirect(http.StatusFound, "/login")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// otpVerifiedRequired требует уже подтверждённую вторым фактором сессию; используется
+// для действий вроде /otp/disable, которые не должны быть доступны до otp_verify.
+func otpVerifiedRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := getSession(c)
+		if !session.LoggedIn {
+			clearSession(c)
+			c.Redirect(http.StatusFound, "/login")
+			c.Abort()
+			return
+		}
+		if !session.OTPVerified {
+			c.Redirect(http.StatusFound, "/otp/verify")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// getSession читает пользователя из сессии и проверяет idle/absolute таймауты
+func getSession(c *gin.Context) *UserSession {
+	var result UserSession
+
+	sess := sessions.Default(c)
+
+	username, _ := sess.Get(sessionKeyUser).(string)
+	if username == "" {
+		return &result
+	}
+
+	groupsJSON, _ := sess.Get(sessionKeyGroups).(string)
+	var groups []string
+	json.Unmarshal([]byte(groupsJSON), &groups)
+
+	loginAtUnix, _ := sess.Get(sessionKeyLoginAt).(int64)
+	lastSeenUnix, _ := sess.Get(sessionKeyLastSeen).(int64)
+
+	now := time.Now()
+	if cfg.SessionAbsTTL > 0 && now.Sub(time.Unix(loginAtUnix, 0)) > cfg.SessionAbsTTL {
+		return &result
+	}
+	if cfg.SessionIdleTTL > 0 && now.Sub(time.Unix(lastSeenUnix, 0)) > cfg.SessionIdleTTL {
+		return &result
+	}
+
+	sess.Set(sessionKeyLastSeen, now.Unix())
+	sess.Save()
+
+	otpVerified, _ := sess.Get(sessionKeyOTPVerified).(bool)
+
+	result = UserSession{
+		Username:    username,
+		Groups:      groups,
+		Role:        roleForGroups(groups),
+		LoggedIn:    true,
+		OTPVerified: otpVerified,
+	}
+	return &result
+}
+
+// setSession открывает новую сессию после успешного логина
+func setSession(c *gin.Context, username string, groups []string) {
+	groupsJSON, _ := json.Marshal(groups)
+
+	sess := sessions.Default(c)
+	now := time.Now().Unix()
+	sess.Set(sessionKeyUser, username)
+	sess.Set(sessionKeyGroups, string(groupsJSON))
+	sess.Set(sessionKeyLoginAt, now)
+	sess.Set(sessionKeyLastSeen, now)
+	sess.Set(sessionKeyOTPVerified, false)
+	sess.Save()
+}
+
+// setOTPVerified отмечает текущую сессию как прошедшую проверку второго фактора.
+func setOTPVerified(c *gin.Context) {
+	sess := sessions.Default(c)
+	sess.Set(sessionKeyOTPVerified, true)
+	sess.Save()
+}
+
+// setOTPPending сохраняет кандидатный секрет и хэши резервных кодов в сессии до
+// подтверждения кодом — до этого момента user_otp в БД не трогается, чтобы
+// повторный GET /otp/enroll не инвалидировал уже настроенную 2FA.
+func setOTPPending(c *gin.Context, secret, hashedCodesJSON string) {
+	sess := sessions.Default(c)
+	sess.Set(sessionKeyOTPPendingSecret, secret)
+	sess.Set(sessionKeyOTPPendingCodes, hashedCodesJSON)
+	sess.Save()
+}
+
+// getOTPPending читает кандидатный секрет и хэши резервных кодов, отложенные setOTPPending.
+func getOTPPending(c *gin.Context) (secret, hashedCodesJSON string, ok bool) {
+	sess := sessions.Default(c)
+	secret, _ = sess.Get(sessionKeyOTPPendingSecret).(string)
+	hashedCodesJSON, _ = sess.Get(sessionKeyOTPPendingCodes).(string)
+	return secret, hashedCodesJSON, secret != ""
+}
+
+// clearOTPPending удаляет кандидатный секрет из сессии после подтверждения или отказа от него.
+func clearOTPPending(c *gin.Context) {
+	sess := sessions.Default(c)
+	sess.Delete(sessionKeyOTPPendingSecret)
+	sess.Delete(sessionKeyOTPPendingCodes)
+	sess.Save()
+}
+
+// clearSession инвалидирует сессию на стороне сервера (а не только очищает cookie)
+func clearSession(c *gin.Context) {
+	sess := sessions.Default(c)
+	sess.Clear()
+	sess.Options(sessions.Options{Path: "/", MaxAge: -1})
+	sess.Save()
+}
+
+// csrfToken возвращает текущий CSRF-токен сессии, создавая его при первом обращении
+func csrfToken(c *gin.Context) string {
+	sess := sessions.Default(c)
+
+	token, _ := sess.Get(sessionKeyCSRF).(string)
+	if token == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			logger.Error("failed to generate csrf token", "error", err)
+		}
+		token = base64.RawURLEncoding.EncodeToString(buf)
+		sess.Set(sessionKeyCSRF, token)
+		sess.Save()
+	}
+	return token
+}
+
+// csrfRequired проверяет X-CSRF-Token/csrf_token против значения, хранимого в сессии
+func csrfRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess := sessions.Default(c)
+		expected, _ := sess.Get(sessionKeyCSRF).(string)
+
+		submitted := c.PostForm("csrf_token")
+		if submitted == "" {
+			submitted = c.GetHeader("X-CSRF-Token")
+		}
+
+		if expected == "" || submitted == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(submitted)) != 1 {
+			c.HTML(http.StatusForbidden, "error.html", gin.H{
+				"Error": "Неверный CSRF-токен, обновите страницу и попробуйте снова",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
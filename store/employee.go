@@ -0,0 +1,31 @@
+// Package store предоставляет доступ к таблице сотрудников через EmployeeStore,
+// скрывая SQL и управление подготовленными выражениями от остального приложения.
+package store
+
+import "time"
+
+// Employee — запись сотрудника в справочнике.
+type Employee struct {
+	ID         int       `json:"id" db:"id"`
+	LastName   string    `json:"last_name" db:"last_name"`
+	FirstName  string    `json:"first_name" db:"first_name"`
+	MiddleName string    `json:"middle_name" db:"middle_name"`
+	Position   string    `json:"position" db:"position"`
+	Phone      string    `json:"phone" db:"phone"`
+	Email      string    `json:"email" db:"email"`
+	Building   string    `json:"building" db:"building"`
+	Comments   string    `json:"comments" db:"comments"`
+	Status     string    `json:"status" db:"status"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Filter задаёт условия и пагинацию для List.
+type Filter struct {
+	Building string
+	Status   string
+	Limit    int
+	Offset   int
+}
+
+// defaultLimit используется, если вызывающий код не задал Limit (или задал 0/отрицательное значение).
+const defaultLimit = 50
@@ -0,0 +1,349 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// tableNameRe ограничивает DB_TABLE безопасным идентификатором, чтобы имя таблицы
+// можно было один раз подставить в текст запроса при подготовке стейтментов.
+var tableNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidateTableName проверяет DB_TABLE на этапе старта приложения, до любого обращения к БД.
+func ValidateTableName(name string) error {
+	if !tableNameRe.MatchString(name) {
+		return fmt.Errorf("invalid table name %q: must match %s", name, tableNameRe.String())
+	}
+	return nil
+}
+
+// minTsQueryLen — запросы короче этой длины не имеют смысла как to_tsquery и
+// обрабатываются через ILIKE по префиксу.
+const minTsQueryLen = 3
+
+// PostgresStore — реализация EmployeeStore поверх *sql.DB с кэшированными
+// подготовленными выражениями для одной проверенной таблицы.
+type PostgresStore struct {
+	db    *sql.DB
+	table string
+
+	listStmt        *sql.Stmt
+	getStmt         *sql.Stmt
+	searchTsStmt    *sql.Stmt
+	searchLikeStmt  *sql.Stmt
+	createStmt      *sql.Stmt
+	updateStmt      *sql.Stmt
+	deleteStmt      *sql.Stmt
+}
+
+// NewPostgresStore проверяет имя таблицы и готовит все запросы один раз при старте.
+func NewPostgresStore(db *sql.DB, table string) (*PostgresStore, error) {
+	if err := ValidateTableName(table); err != nil {
+		return nil, err
+	}
+
+	s := &PostgresStore{db: db, table: table}
+
+	stmts := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&s.listStmt, fmt.Sprintf(`
+			SELECT id, last_name, first_name, middle_name, position,
+				   phone, email, building, comments, status, created_at
+			FROM %s
+			WHERE ($1 = '' OR building = $1) AND ($2 = '' OR status = $2)
+			ORDER BY last_name, first_name, id
+			LIMIT $3 OFFSET $4
+		`, table)},
+		{&s.getStmt, fmt.Sprintf(`
+			SELECT id, last_name, first_name, middle_name, position,
+				   phone, email, building, comments, status, created_at
+			FROM %s
+			WHERE id = $1
+		`, table)},
+		{&s.searchTsStmt, fmt.Sprintf(`
+			SELECT id, last_name, first_name, middle_name, position,
+				   phone, email, building, comments, status, created_at
+			FROM %s
+			WHERE search_vector @@ to_tsquery('russian', $1)
+			ORDER BY ts_rank(search_vector, to_tsquery('russian', $1)) DESC, last_name, first_name, id
+			LIMIT $2 OFFSET $3
+		`, table)},
+		{&s.searchLikeStmt, fmt.Sprintf(`
+			SELECT id, last_name, first_name, middle_name, position,
+				   phone, email, building, comments, status, created_at
+			FROM %s
+			WHERE last_name ILIKE $1 OR first_name ILIKE $1 OR
+				  middle_name ILIKE $1 OR position ILIKE $1 OR
+				  phone ILIKE $1 OR email ILIKE $1
+			ORDER BY last_name, first_name, id
+			LIMIT $2 OFFSET $3
+		`, table)},
+		{&s.createStmt, fmt.Sprintf(`
+			INSERT INTO %s (last_name, first_name, middle_name, position, phone, email, building, comments, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, table)},
+		{&s.updateStmt, fmt.Sprintf(`
+			UPDATE %s SET last_name = $1, first_name = $2, middle_name = $3, position = $4,
+				phone = $5, email = $6, building = $7, comments = $8, status = $9
+			WHERE id = $10
+		`, table)},
+		{&s.deleteStmt, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table)},
+	}
+
+	for _, st := range stmts {
+		prepared, err := db.Prepare(st.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		*st.dst = prepared
+	}
+
+	return s, nil
+}
+
+func scanEmployee(row interface{ Scan(...interface{}) error }) (Employee, error) {
+	var emp Employee
+	err := row.Scan(
+		&emp.ID, &emp.LastName, &emp.FirstName, &emp.MiddleName,
+		&emp.Position, &emp.Phone, &emp.Email, &emp.Building,
+		&emp.Comments, &emp.Status, &emp.CreatedAt,
+	)
+	return emp, err
+}
+
+func collectRows(rows *sql.Rows) ([]Employee, error) {
+	defer rows.Close()
+
+	var employees []Employee
+	for rows.Next() {
+		emp, err := scanEmployee(rows)
+		if err != nil {
+			return nil, err
+		}
+		employees = append(employees, emp)
+	}
+	return employees, rows.Err()
+}
+
+func withLimit(limit int) int {
+	return EffectiveLimit(limit)
+}
+
+// EffectiveLimit возвращает limit, который в итоге применит List/Search: переданное
+// значение, если оно положительное, иначе defaultLimit. Вызывающий код должен
+// использовать именно это значение (а не исходный limit=0 из запроса) везде, где
+// оно показывается пользователю или участвует в построении следующих запросов —
+// иначе пагинация "зависает" на limit=0.
+func EffectiveLimit(limit int) int {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	return limit
+}
+
+func (s *PostgresStore) List(ctx context.Context, filter Filter) ([]Employee, error) {
+	rows, err := s.listStmt.QueryContext(ctx, filter.Building, filter.Status, withLimit(filter.Limit), filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return collectRows(rows)
+}
+
+func (s *PostgresStore) Get(ctx context.Context, id int) (*Employee, error) {
+	emp, err := scanEmployee(s.getStmt.QueryRowContext(ctx, id))
+	if err != nil {
+		return nil, err
+	}
+	return &emp, nil
+}
+
+// Search использует полнотекстовый поиск через to_tsquery для запросов длиной от
+// minTsQueryLen символов и откатывается на ILIKE для коротких префиксов, пустых
+// tsquery и любых ошибок самого to_tsquery (например, не до конца отфильтрованный синтаксис).
+func (s *PostgresStore) Search(ctx context.Context, query string, limit, offset int) ([]Employee, error) {
+	limit = withLimit(limit)
+
+	if utf8.RuneCountInString(strings.TrimSpace(query)) < minTsQueryLen {
+		return s.searchLike(ctx, query, limit, offset)
+	}
+
+	tsQuery := toTsQuery(query)
+	if tsQuery == "" {
+		return s.searchLike(ctx, query, limit, offset)
+	}
+
+	rows, err := s.searchTsStmt.QueryContext(ctx, tsQuery, limit, offset)
+	if err != nil {
+		// Невалидный tsquery (редкий остаточный случай) не должен всплывать пользователю как 500 —
+		// откатываемся на ILIKE так же, как и для короткого/пустого запроса.
+		return s.searchLike(ctx, query, limit, offset)
+	}
+
+	employees, err := collectRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// to_tsquery с пустым результатом разбора (например, один стоп-символ) не ошибка,
+	// но и не находит ничего осмысленного — подстрахуемся ILIKE-фоллбэком.
+	if len(employees) == 0 {
+		return s.searchLike(ctx, query, limit, offset)
+	}
+
+	return employees, nil
+}
+
+func (s *PostgresStore) searchLike(ctx context.Context, query string, limit, offset int) ([]Employee, error) {
+	rows, err := s.searchLikeStmt.QueryContext(ctx, "%"+query+"%", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return collectRows(rows)
+}
+
+// tsTermRe оставляет в токене только буквы (включая кириллицу) и цифры: весь остальной
+// синтаксис tsquery (&|!():' и т.п.) вырезается целиком, а не только по краям токена.
+var tsTermRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// toTsQuery превращает многословный пользовательский запрос в AND-связку term:* для to_tsquery.
+func toTsQuery(query string) string {
+	fields := strings.Fields(query)
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = tsTermRe.ReplaceAllString(f, "")
+		if f == "" {
+			continue
+		}
+		terms = append(terms, f+":*")
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	return strings.Join(terms, " & ")
+}
+
+func (s *PostgresStore) Create(ctx context.Context, emp *Employee) (int, error) {
+	var id int
+	err := s.createStmt.QueryRowContext(ctx,
+		emp.LastName, emp.FirstName, emp.MiddleName, emp.Position,
+		emp.Phone, emp.Email, emp.Building, emp.Comments, emp.Status,
+	).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) Update(ctx context.Context, emp *Employee) error {
+	res, err := s.updateStmt.ExecContext(ctx,
+		emp.LastName, emp.FirstName, emp.MiddleName, emp.Position,
+		emp.Phone, emp.Email, emp.Building, emp.Comments, emp.Status, emp.ID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, id int) error {
+	res, err := s.deleteStmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Import upserts rows by the configured natural key inside a single transaction,
+// rolling back entirely if any row fails.
+func (s *PostgresStore) Import(ctx context.Context, rows []Employee, key NaturalKey) (ImportSummary, error) {
+	summary := ImportSummary{Total: len(rows)}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback()
+
+	findQuery, findArgs, keyApplies := s.naturalKeyQuery(key)
+
+	for i, emp := range rows {
+		var existingID int
+		err := sql.ErrNoRows
+		if keyApplies(emp) {
+			err = tx.QueryRowContext(ctx, findQuery, findArgs(emp)...).Scan(&existingID)
+		}
+
+		switch {
+		case err == sql.ErrNoRows:
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+				`INSERT INTO %s (last_name, first_name, middle_name, position, phone, email, building, comments, status)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, s.table),
+				emp.LastName, emp.FirstName, emp.MiddleName, emp.Position,
+				emp.Phone, emp.Email, emp.Building, emp.Comments, emp.Status,
+			); err != nil {
+				summary.Errors = append(summary.Errors, RowError{Row: i + 1, Message: err.Error()})
+				continue
+			}
+			summary.Created++
+		case err != nil:
+			summary.Errors = append(summary.Errors, RowError{Row: i + 1, Message: err.Error()})
+			continue
+		default:
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+				`UPDATE %s SET last_name = $1, first_name = $2, middle_name = $3, position = $4,
+					phone = $5, email = $6, building = $7, comments = $8, status = $9
+				 WHERE id = $10`, s.table),
+				emp.LastName, emp.FirstName, emp.MiddleName, emp.Position,
+				emp.Phone, emp.Email, emp.Building, emp.Comments, emp.Status, existingID,
+			); err != nil {
+				summary.Errors = append(summary.Errors, RowError{Row: i + 1, Message: err.Error()})
+				continue
+			}
+			summary.Updated++
+		}
+	}
+
+	if len(summary.Errors) > 0 {
+		return summary, fmt.Errorf("import failed for %d of %d rows", len(summary.Errors), summary.Total)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// naturalKeyQuery возвращает запрос поиска существующей записи по ключу, функцию
+// извлечения его аргументов из Employee и предикат, допускающий поиск по этому
+// ключу для данной записи (email необязателен в этой адресной книге, поэтому
+// пустой email не должен матчиться на другие пустые email при апсерте).
+func (s *PostgresStore) naturalKeyQuery(key NaturalKey) (string, func(Employee) []interface{}, func(Employee) bool) {
+	switch key {
+	case NaturalKeyFIO:
+		return fmt.Sprintf(`SELECT id FROM %s WHERE last_name = $1 AND first_name = $2 AND middle_name = $3`, s.table),
+			func(e Employee) []interface{} { return []interface{}{e.LastName, e.FirstName, e.MiddleName} },
+			func(e Employee) bool { return true }
+	default:
+		return fmt.Sprintf(`SELECT id FROM %s WHERE email = $1`, s.table),
+			func(e Employee) []interface{} { return []interface{}{e.Email} },
+			func(e Employee) bool { return e.Email != "" }
+	}
+}
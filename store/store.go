@@ -0,0 +1,39 @@
+package store
+
+import "context"
+
+// NaturalKey задаёт, по какому полю import должен искать существующую запись при upsert.
+type NaturalKey string
+
+const (
+	NaturalKeyEmail NaturalKey = "email"
+	NaturalKeyFIO   NaturalKey = "fio"
+)
+
+// ImportSummary описывает результат Import: сколько строк создано/обновлено и какие не прошли запись.
+type ImportSummary struct {
+	Total   int
+	Created int
+	Updated int
+	Errors  []RowError
+}
+
+// RowError — ошибка записи конкретной строки импорта (1-indexed, без учёта заголовка).
+type RowError struct {
+	Row     int
+	Message string
+}
+
+// EmployeeStore — доступ к хранилищу сотрудников. Все методы контекстно-зависимы,
+// чтобы отмена запроса/таймаут на уровне HTTP-хендлера доходил до SQL-драйвера.
+type EmployeeStore interface {
+	List(ctx context.Context, filter Filter) ([]Employee, error)
+	Get(ctx context.Context, id int) (*Employee, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]Employee, error)
+	Create(ctx context.Context, emp *Employee) (int, error)
+	Update(ctx context.Context, emp *Employee) error
+	Delete(ctx context.Context, id int) error
+
+	// Import upserts rows by key inside a single transaction and returns a per-row summary.
+	Import(ctx context.Context, rows []Employee, key NaturalKey) (ImportSummary, error)
+}